@@ -0,0 +1,62 @@
+package audit_log
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/hanko/backend/config"
+	"github.com/teamhanko/hanko/backend/persistence"
+	"github.com/teamhanko/hanko/backend/persistence/models"
+)
+
+// Type identifies the kind of event being recorded in the audit log.
+type Type string
+
+const (
+	TokenExchangeSucceeded     Type = "token_exchange_succeeded"
+	TokenExchangeFailed        Type = "token_exchange_failed"
+	TokenRevoked               Type = "token_revoked"
+	TokenIntrospectionActive   Type = "token_introspection_active"
+	TokenIntrospectionInactive Type = "token_introspection_inactive"
+	TokenReuseDetected         Type = "token_reuse_detected"
+	TokenIssuanceRateLimited   Type = "token_issuance_rate_limited"
+	TokenPKCEMissing           Type = "token_pkce_missing"
+	TokenPKCEMismatch          Type = "token_pkce_mismatch"
+)
+
+// Logger records audit log entries for security relevant events.
+type Logger interface {
+	Create(c echo.Context, auditLogType Type, user *models.User, details error) error
+}
+
+type logger struct {
+	storage persistence.Storage
+	config  config.AuditLog
+}
+
+// NewLogger creates an audit log Logger that persists entries through the
+// given storage, honoring the AuditLog configuration.
+func NewLogger(storage persistence.Storage, cfg config.AuditLog) Logger {
+	return &logger{storage: storage, config: cfg}
+}
+
+func (l *logger) Create(c echo.Context, auditLogType Type, user *models.User, details error) error {
+	if !l.config.Storage.Enabled {
+		return nil
+	}
+
+	var userId string
+	if user != nil {
+		userId = user.ID.String()
+	}
+
+	var errorMessage string
+	if details != nil {
+		errorMessage = details.Error()
+	}
+
+	entry, err := models.NewAuditLog(string(auditLogType), userId, c.RealIP(), errorMessage)
+	if err != nil {
+		return err
+	}
+
+	return l.storage.GetAuditLogPersister().Create(*entry)
+}
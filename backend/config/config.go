@@ -0,0 +1,34 @@
+package config
+
+// Config is the root application configuration.
+type Config struct {
+	Database     Database     `yaml:"database" json:"database"`
+	Session      Session      `yaml:"session" json:"session"`
+	AuditLog     AuditLog     `yaml:"audit_log" json:"audit_log"`
+	Logging      Logging      `yaml:"logging" json:"logging"`
+	Email        Email        `yaml:"email" json:"email"`
+	SMS          SMS          `yaml:"sms" json:"sms"`
+	WebPush      WebPush      `yaml:"web_push" json:"web_push"`
+	RateLimiting RateLimiting `yaml:"rate_limiting" json:"rate_limiting"`
+	Token        Token        `yaml:"token" json:"token"`
+}
+
+// Database holds the connection settings for the persistence layer.
+type Database struct {
+	Url string `yaml:"url" json:"url"`
+}
+
+// Session configures how session JWTs and cookies are issued.
+type Session struct {
+	EnableAuthTokenHeader bool `yaml:"enable_auth_token_header" json:"enable_auth_token_header"`
+}
+
+// AuditLog configures audit log emission and storage.
+type AuditLog struct {
+	Storage AuditLogStorage `yaml:"storage" json:"storage"`
+}
+
+// AuditLogStorage toggles persistence of audit log entries.
+type AuditLogStorage struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
@@ -0,0 +1,73 @@
+package config
+
+// Email configures outgoing transactional email used to deliver one-time
+// tokens.
+type Email struct {
+	FromAddress string     `yaml:"from_address" json:"from_address"`
+	SMTP        SMTPConfig `yaml:"smtp" json:"smtp"`
+}
+
+// SMTPConfig holds credentials for the SMTP server EmailDeliverer sends
+// through.
+type SMTPConfig struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     string `yaml:"port" json:"port"`
+	User     string `yaml:"user" json:"user"`
+	Password string `yaml:"password" json:"password"`
+}
+
+// SMSProvider identifies which upstream SMS API SMSDeliverer talks to.
+type SMSProvider string
+
+const (
+	SMSProviderTwilio SMSProvider = "twilio"
+	SMSProviderVonage SMSProvider = "vonage"
+)
+
+// SMS configures outgoing SMS/WhatsApp delivery of one-time tokens.
+type SMS struct {
+	Provider SMSProvider  `yaml:"provider" json:"provider"`
+	Twilio   TwilioConfig `yaml:"twilio" json:"twilio"`
+	Vonage   VonageConfig `yaml:"vonage" json:"vonage"`
+}
+
+// TwilioConfig holds credentials for the Twilio Programmable Messaging API.
+type TwilioConfig struct {
+	AccountSID string `yaml:"account_sid" json:"account_sid"`
+	AuthToken  string `yaml:"auth_token" json:"auth_token"`
+	FromNumber string `yaml:"from_number" json:"from_number"`
+}
+
+// VonageConfig holds credentials for the Vonage SMS API.
+type VonageConfig struct {
+	APIKey    string `yaml:"api_key" json:"api_key"`
+	APISecret string `yaml:"api_secret" json:"api_secret"`
+	FromName  string `yaml:"from_name" json:"from_name"`
+}
+
+// WebPush configures outgoing Web Push delivery of one-time tokens.
+type WebPush struct {
+	VAPIDPublicKey  string `yaml:"vapid_public_key" json:"vapid_public_key"`
+	VAPIDPrivateKey string `yaml:"vapid_private_key" json:"vapid_private_key"`
+	Subscriber      string `yaml:"subscriber" json:"subscriber"`
+}
+
+// RateLimiting configures the token-issuance rate limiter.
+type RateLimiting struct {
+	// Requests is the number of token issuances allowed per Interval for a
+	// given (user, channel, ip) bucket.
+	Requests int `yaml:"requests" json:"requests"`
+	// IntervalSeconds is the leaky-bucket refill interval.
+	IntervalSeconds int `yaml:"interval_seconds" json:"interval_seconds"`
+	// Redis holds settings for a shared limiter store. Only the in-process
+	// limiter exists: with Redis.Enabled set, NewTokenIssuanceRateLimiter
+	// returns an error instead of a limiter.
+	Redis RedisConfig `yaml:"redis" json:"redis"`
+}
+
+// RedisConfig holds the settings for a Redis rate limiter store. No
+// Redis-backed limiter is built from it; see RateLimiting.Redis.
+type RedisConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Address string `yaml:"address" json:"address"`
+}
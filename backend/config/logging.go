@@ -0,0 +1,11 @@
+package config
+
+// Logging configures the root slog.Logger handlers are given.
+type Logging struct {
+	// Format is either "json" or "text".
+	Format string `yaml:"format" json:"format"`
+	// Level is one of "debug", "info", "warn", "error".
+	Level string `yaml:"level" json:"level"`
+	// AddSource includes the source file/line of each log call.
+	AddSource bool `yaml:"add_source" json:"add_source"`
+}
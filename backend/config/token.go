@@ -0,0 +1,11 @@
+package config
+
+// Token configures issuance and exchange of one-time tokens.
+type Token struct {
+	// RequirePKCE rejects token creation requests that don't supply a
+	// code_challenge, forcing every token issued while this is enabled to
+	// be PKCE-bound. Tokens issued before it was enabled, or while it was
+	// disabled, keep working as before: binding is stored per-token, not
+	// enforced retroactively.
+	RequirePKCE bool `yaml:"require_pkce" json:"require_pkce"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "fmt"
+
+// HTTPError is the error type returned by handlers and rendered as the
+// JSON error body of API responses.
+type HTTPError struct {
+	Code     int    `json:"-"`
+	Message  string `json:"error"`
+	Internal error  `json:"-"`
+}
+
+func (e *HTTPError) Error() string {
+	if e.Internal != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Internal.Error())
+	}
+	return e.Message
+}
+
+// NewHTTPError creates an HTTPError with the given status code and an
+// optional message. If no message is given, Message is left empty; an
+// underlying error can still be attached via SetInternal.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	herr := &HTTPError{Code: code}
+	if len(message) > 0 {
+		herr.Message = message[0]
+	}
+	return herr
+}
+
+// SetInternal attaches an internal error for logging purposes without
+// exposing it in the JSON response.
+func (e *HTTPError) SetInternal(err error) *HTTPError {
+	e.Internal = err
+	return e
+}
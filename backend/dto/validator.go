@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// CustomValidator adapts go-playground/validator to echo's Validator
+// interface.
+type CustomValidator struct {
+	validator *validator.Validate
+}
+
+// NewCustomValidator creates a CustomValidator using the default
+// validator tag conventions used throughout the request DTOs. Fields are
+// reported by their json name.
+func NewCustomValidator() *CustomValidator {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	})
+	return &CustomValidator{validator: v}
+}
+
+// Validate reports a missing required field as "<field> is a required
+// field"; several failures are joined with " and ".
+func (cv *CustomValidator) Validate(i interface{}) error {
+	var vErrs validator.ValidationErrors
+	if err := cv.validator.Struct(i); !errors.As(err, &vErrs) {
+		return err
+	}
+	msgs := make([]string, len(vErrs))
+	for n, fe := range vErrs {
+		msgs[n] = fe.Error()
+		if fe.Tag() == "required" {
+			msgs[n] = fmt.Sprintf("%s is a required field", fe.Field())
+		}
+	}
+	return errors.New(strings.Join(msgs, " and "))
+}
@@ -0,0 +1,388 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	auditlog "github.com/teamhanko/hanko/backend/audit_log"
+	"github.com/teamhanko/hanko/backend/config"
+	"github.com/teamhanko/hanko/backend/dto"
+	"github.com/teamhanko/hanko/backend/persistence"
+	"github.com/teamhanko/hanko/backend/persistence/models"
+	"github.com/teamhanko/hanko/backend/session"
+)
+
+// TokenHandler exchanges one-time tokens (e.g. issued after a third party
+// sign-in callback) for a Hanko session, and lets clients manage the
+// lifecycle of those tokens.
+type TokenHandler struct {
+	cfg             *config.Config
+	persister       persistence.Storage
+	sessionManager  session.Manager
+	auditLogger     auditlog.Logger
+	logger          *slog.Logger
+	deliveryService *TokenDeliveryService
+	rateLimiter     TokenIssuanceRateLimiter
+}
+
+// NewTokenHandler creates a TokenHandler.
+func NewTokenHandler(cfg *config.Config, persister persistence.Storage, sessionManager session.Manager, auditLogger auditlog.Logger, logger *slog.Logger, deliveryService *TokenDeliveryService, rateLimiter TokenIssuanceRateLimiter) *TokenHandler {
+	return &TokenHandler{
+		cfg:             cfg,
+		persister:       persister,
+		sessionManager:  sessionManager,
+		auditLogger:     auditLogger,
+		logger:          logger,
+		deliveryService: deliveryService,
+		rateLimiter:     rateLimiter,
+	}
+}
+
+// requestLogger returns a child logger carrying the request id, the
+// caller's remote IP and, once known, the resolved user. Validate adds the
+// token_id field itself, as a hash of the token value. The raw token is
+// never logged — only its hash, so log output can't be replayed as a
+// credential.
+func (h *TokenHandler) requestLogger(c echo.Context, userId string) *slog.Logger {
+	logger := h.logger.With(
+		slog.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID)),
+		slog.String("remote_ip", c.RealIP()),
+	)
+
+	if userId != "" {
+		logger = logger.With(slog.String("user_id", userId))
+	}
+
+	return logger
+}
+
+func hashTokenValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenValidationBody is the request body for exchanging a one-time token.
+type TokenValidationBody struct {
+	Value string `json:"value" validate:"required"`
+	// CodeVerifier is required when the token was issued with a
+	// code_challenge (see TokenCreationBody), per RFC 7636.
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// Validate exchanges a one-time token for a Hanko session. The exchange is
+// performed as an atomic claim (TokenPersister.MarkUsed) rather than a
+// read-then-delete, so two concurrent exchanges of the same token value can
+// never both succeed: the loser is treated as a replay, not a race.
+func (h *TokenHandler) Validate(c echo.Context) error {
+	var body TokenValidationBody
+	if err := c.Bind(&body); err != nil {
+		h.requestLogger(c, "").Warn("token exchange rejected: malformed request body", slog.Any("error", err))
+		return h.failValidation(c, nil, dto.NewHTTPError(http.StatusBadRequest).SetInternal(err))
+	}
+
+	if err := c.Validate(body); err != nil {
+		h.requestLogger(c, "").Warn("token exchange rejected: validation failed", slog.Any("error", err))
+		return h.failValidation(c, nil, dto.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err))
+	}
+
+	logger := h.requestLogger(c, "").With(slog.String("token_id", hashTokenValue(body.Value)))
+
+	token, err := h.persister.GetTokenPersister().GetByValue(body.Value)
+	if err != nil {
+		return h.failValidation(c, nil, dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err))
+	}
+
+	if token == nil {
+		logger.Warn("token exchange rejected: token not found")
+		return h.failValidation(c, nil, dto.NewHTTPError(http.StatusNotFound, "token not found"))
+	}
+
+	logger = h.requestLogger(c, token.UserId.String()).With(slog.String("token_id", hashTokenValue(body.Value)))
+
+	if token.IsExpired() {
+		logger.Warn("token exchange rejected: token has expired")
+		return h.failValidation(c, token, dto.NewHTTPError(http.StatusUnprocessableEntity, "token has expired"))
+	}
+
+	if token.CodeChallenge != nil {
+		if body.CodeVerifier == "" {
+			logger.Warn("token exchange rejected: missing code_verifier")
+			return h.failPKCE(c, token, auditlog.TokenPKCEMissing)
+		}
+
+		if !verifyCodeVerifier(body.CodeVerifier, *token.CodeChallenge) {
+			logger.Warn("token exchange rejected: code_verifier does not match code_challenge")
+			return h.failPKCE(c, token, auditlog.TokenPKCEMismatch)
+		}
+	}
+
+	jwt, err := h.sessionManager.GenerateJWT(token.UserId)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	claimed, won, err := h.persister.GetTokenPersister().MarkUsed(token.Value, jwt)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	if !won {
+		logger.Error("token exchange rejected: token has already been used")
+		return h.failReuse(c, claimed)
+	}
+
+	cookie, err := h.sessionManager.GenerateCookie(jwt)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	c.SetCookie(cookie)
+	if h.cfg.Session.EnableAuthTokenHeader {
+		c.Response().Header().Set("X-Auth-Token", jwt)
+	}
+
+	user := &models.User{ID: token.UserId}
+	if err := h.auditLogger.Create(c, auditlog.TokenExchangeSucceeded, user, nil); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	logger.Info("token exchange succeeded")
+	return c.NoContent(http.StatusOK)
+}
+
+// failReuse handles a losing MarkUsed attempt: the token was already
+// exchanged by another caller. The session that exchange produced is
+// invalidated, since a second exchange attempt is treated as a signal that
+// the token value leaked, not as harmless contention.
+func (h *TokenHandler) failReuse(c echo.Context, claimed *models.Token) error {
+	herr := dto.NewHTTPError(http.StatusConflict, "token has already been used")
+
+	if claimed == nil {
+		return h.failValidation(c, nil, herr)
+	}
+
+	if claimed.IssuedSessionJWT != "" {
+		if err := h.sessionManager.Invalidate(claimed.IssuedSessionJWT); err != nil {
+			return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+	}
+
+	user := &models.User{ID: claimed.UserId}
+	if err := h.auditLogger.Create(c, auditlog.TokenReuseDetected, user, herr); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	return herr
+}
+
+// failPKCE rejects an exchange that didn't satisfy the token's PKCE
+// binding, per RFC 6749 section 5.2's invalid_grant error for a failed
+// verifier check.
+func (h *TokenHandler) failPKCE(c echo.Context, token *models.Token, auditLogType auditlog.Type) error {
+	herr := dto.NewHTTPError(http.StatusBadRequest, "invalid_grant")
+
+	user := &models.User{ID: token.UserId}
+	if err := h.auditLogger.Create(c, auditLogType, user, herr); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	return herr
+}
+
+// failValidation records a failed token exchange attempt in the audit log
+// and returns the given error to the caller.
+func (h *TokenHandler) failValidation(c echo.Context, token *models.Token, herr *dto.HTTPError) error {
+	var user *models.User
+	if token != nil {
+		user = &models.User{ID: token.UserId}
+	}
+
+	if err := h.auditLogger.Create(c, auditlog.TokenExchangeFailed, user, herr); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	return herr
+}
+
+// TokenCreationBody is the request body for POST /token, which issues a
+// new one-time token for an already-authenticated-by-other-means user and
+// delivers it over the requested channel.
+type TokenCreationBody struct {
+	UserId  string `json:"user_id" validate:"required"`
+	Channel string `json:"channel"`
+	// CodeChallenge and CodeChallengeMethod optionally bind the issued
+	// token to a PKCE (RFC 7636) code_verifier that must be presented on
+	// exchange. CodeChallengeMethod must be "S256" when CodeChallenge is
+	// set. If cfg.Token.RequirePKCE is enabled, CodeChallenge is required.
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// Create issues a new one-time token for a user and delivers it over a
+// channel, subject to a per-(user, channel, ip) rate limit.
+func (h *TokenHandler) Create(c echo.Context) error {
+	var body TokenCreationBody
+	if err := c.Bind(&body); err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest).SetInternal(err)
+	}
+
+	if err := c.Validate(body); err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	if body.CodeChallenge == "" && h.cfg.Token.RequirePKCE {
+		return dto.NewHTTPError(http.StatusBadRequest, "code_challenge is required")
+	}
+
+	if body.CodeChallenge != "" && !validCodeChallengeMethod(body.CodeChallengeMethod) {
+		return dto.NewHTTPError(http.StatusBadRequest, "code_challenge_method must be S256")
+	}
+
+	userId, err := uuid.FromString(body.UserId)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest, "user_id is not a valid uuid").SetInternal(err)
+	}
+
+	channel := DeliveryChannel(body.Channel)
+
+	if h.rateLimiter != nil && !h.rateLimiter.Allow(RateLimitKey(body.UserId, channel, c.RealIP())) {
+		if err := h.auditLogger.Create(c, auditlog.TokenIssuanceRateLimited, &models.User{ID: userId}, nil); err != nil {
+			return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		return dto.NewHTTPError(http.StatusTooManyRequests, "too many token requests")
+	}
+
+	user, err := h.persister.GetUserPersister().Get(userId)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	if user == nil {
+		return dto.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+
+	token, err := models.NewToken(userId)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	if body.CodeChallenge != "" {
+		token.CodeChallenge = &body.CodeChallenge
+		token.CodeChallengeMethod = &body.CodeChallengeMethod
+	}
+
+	if err := h.persister.GetTokenPersister().Create(*token); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	usedChannel, err := h.deliveryService.Deliver(c.Request().Context(), *user, *token, channel)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	h.requestLogger(c, userId.String()).Info("token issued", slog.String("channel", string(usedChannel)))
+
+	return c.NoContent(http.StatusCreated)
+}
+
+// TokenRevocationBody is the request body for POST /token/revoke.
+type TokenRevocationBody struct {
+	Token         string `json:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint"`
+}
+
+// Revoke invalidates an outstanding one-time token before it is exchanged,
+// modelled on the revocation semantics of RFC 7009. Revoking an unknown or
+// already-used token is not an error, per the RFC's guidance to treat
+// revocation as idempotent from the client's perspective.
+func (h *TokenHandler) Revoke(c echo.Context) error {
+	var body TokenRevocationBody
+	if err := c.Bind(&body); err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest).SetInternal(err)
+	}
+
+	if err := c.Validate(body); err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	token, err := h.persister.GetTokenPersister().GetByValue(body.Token)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	if token == nil {
+		if err := h.auditLogger.Create(c, auditlog.TokenRevoked, nil, nil); err != nil {
+			return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		return c.NoContent(http.StatusOK)
+	}
+
+	if err := h.persister.GetTokenPersister().Delete(*token); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	user := &models.User{ID: token.UserId}
+	if err := h.auditLogger.Create(c, auditlog.TokenRevoked, user, nil); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// TokenIntrospectionBody is the request body for POST /token/introspect.
+type TokenIntrospectionBody struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// TokenIntrospectionResponse mirrors the subset of RFC 7662's introspection
+// response that is meaningful for one-time tokens.
+type TokenIntrospectionResponse struct {
+	Active bool   `json:"active"`
+	Sub    string `json:"sub,omitempty"`
+	Exp    int64  `json:"exp,omitempty"`
+	Iat    int64  `json:"iat,omitempty"`
+	Scope  string `json:"scope,omitempty"`
+	Aud    string `json:"aud,omitempty"`
+}
+
+// Introspect reports whether a token value is still active, per RFC 7662.
+// Tokens have no scope/audience concept yet, so those fields are always
+// empty; they are present so clients written against the RFC don't have to
+// special-case this endpoint.
+func (h *TokenHandler) Introspect(c echo.Context) error {
+	var body TokenIntrospectionBody
+	if err := c.Bind(&body); err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest).SetInternal(err)
+	}
+
+	if err := c.Validate(body); err != nil {
+		return dto.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+
+	token, err := h.persister.GetTokenPersister().GetByValue(body.Token)
+	if err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	if token == nil || token.IsExpired() || token.IsUsed() {
+		if err := h.auditLogger.Create(c, auditlog.TokenIntrospectionInactive, nil, nil); err != nil {
+			return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, TokenIntrospectionResponse{Active: false})
+	}
+
+	user := &models.User{ID: token.UserId}
+	if err := h.auditLogger.Create(c, auditlog.TokenIntrospectionActive, user, nil); err != nil {
+		return dto.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, TokenIntrospectionResponse{
+		Active: true,
+		Sub:    token.UserId.String(),
+		Exp:    token.ExpiresAt.Unix(),
+		Iat:    token.CreatedAt.Unix(),
+	})
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/teamhanko/hanko/backend/config"
+	"github.com/teamhanko/hanko/backend/persistence/models"
+)
+
+// DeliveryChannel identifies which side-channel a one-time token is
+// delivered over.
+type DeliveryChannel string
+
+const (
+	ChannelEmail    DeliveryChannel = "email"
+	ChannelSMS      DeliveryChannel = "sms"
+	ChannelWhatsApp DeliveryChannel = "whatsapp"
+	ChannelPush     DeliveryChannel = "push"
+)
+
+// TokenDeliverer sends a one-time token's value to a user over a specific
+// channel. Implementations are looked up by DeliveryChannel in
+// TokenDeliveryService.
+type TokenDeliverer interface {
+	Channel() DeliveryChannel
+	Deliver(ctx context.Context, user models.User, token models.Token) error
+}
+
+// httpClientTimeout bounds how long a deliverer waits on an upstream
+// provider before giving up, so a slow SMS/WhatsApp API can't hang Create.
+const httpClientTimeout = 10 * time.Second
+
+// EmailDeliverer sends the token as a templated login email.
+type EmailDeliverer struct {
+	cfg config.Email
+}
+
+// NewEmailDeliverer creates an EmailDeliverer.
+func NewEmailDeliverer(cfg config.Email) *EmailDeliverer {
+	return &EmailDeliverer{cfg: cfg}
+}
+
+func (d *EmailDeliverer) Channel() DeliveryChannel { return ChannelEmail }
+
+func (d *EmailDeliverer) Deliver(ctx context.Context, user models.User, token models.Token) error {
+	if user.Email == "" {
+		return fmt.Errorf("user %s has no email address on file", user.ID)
+	}
+
+	addr := fmt.Sprintf("%s:%s", d.cfg.SMTP.Host, d.cfg.SMTP.Port)
+	var auth smtp.Auth
+	if d.cfg.SMTP.User != "" {
+		auth = smtp.PlainAuth("", d.cfg.SMTP.User, d.cfg.SMTP.Password, d.cfg.SMTP.Host)
+	}
+
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: Your sign-in code\r\n\r\nYour one-time code is: %s\r\n",
+		d.cfg.FromAddress, user.Email, token.Value,
+	)
+
+	if err := smtp.SendMail(addr, auth, d.cfg.FromAddress, []string{user.Email}, []byte(msg)); err != nil {
+		return fmt.Errorf("email delivery failed: %w", err)
+	}
+
+	return nil
+}
+
+// SMSDeliverer sends the token as a text message through a configurable
+// SMS provider (Twilio or Vonage).
+type SMSDeliverer struct {
+	cfg        config.SMS
+	httpClient *http.Client
+}
+
+// NewSMSDeliverer creates an SMSDeliverer for the configured provider.
+func NewSMSDeliverer(cfg config.SMS) *SMSDeliverer {
+	return &SMSDeliverer{cfg: cfg, httpClient: &http.Client{Timeout: httpClientTimeout}}
+}
+
+func (d *SMSDeliverer) Channel() DeliveryChannel { return ChannelSMS }
+
+func (d *SMSDeliverer) Deliver(ctx context.Context, user models.User, token models.Token) error {
+	if user.PhoneNumber == "" {
+		return fmt.Errorf("user %s has no phone number on file", user.ID)
+	}
+
+	switch d.cfg.Provider {
+	case config.SMSProviderTwilio:
+		return d.deliverViaTwilio(ctx, user.PhoneNumber, token)
+	case config.SMSProviderVonage:
+		return d.deliverViaVonage(ctx, user.PhoneNumber, token)
+	default:
+		return fmt.Errorf("unsupported sms provider: %q", d.cfg.Provider)
+	}
+}
+
+// deliverViaTwilio sends the token value as an SMS body through Twilio's
+// Programmable Messaging API.
+func (d *SMSDeliverer) deliverViaTwilio(ctx context.Context, to string, token models.Token) error {
+	endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", d.cfg.Twilio.AccountSID)
+
+	form := url.Values{
+		"To":   {to},
+		"From": {d.cfg.Twilio.FromNumber},
+		"Body": {token.Value},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(d.cfg.Twilio.AccountSID, d.cfg.Twilio.AuthToken)
+
+	return doDeliveryRequest(d.httpClient, req, "twilio")
+}
+
+// deliverViaVonage sends the token value as an SMS body through Vonage's
+// SMS API.
+func (d *SMSDeliverer) deliverViaVonage(ctx context.Context, to string, token models.Token) error {
+	payload, err := json.Marshal(map[string]string{
+		"api_key":    d.cfg.Vonage.APIKey,
+		"api_secret": d.cfg.Vonage.APISecret,
+		"to":         to,
+		"from":       d.cfg.Vonage.FromName,
+		"text":       token.Value,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://rest.nexmo.com/sms/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doDeliveryRequest(d.httpClient, req, "vonage")
+}
+
+// WhatsAppDeliverer sends the token as a WhatsApp message through Twilio's
+// WhatsApp Messaging API, reusing the Twilio credentials configured for SMS.
+type WhatsAppDeliverer struct {
+	cfg        config.SMS
+	httpClient *http.Client
+}
+
+// NewWhatsAppDeliverer creates a WhatsAppDeliverer backed by Twilio.
+func NewWhatsAppDeliverer(cfg config.SMS) *WhatsAppDeliverer {
+	return &WhatsAppDeliverer{cfg: cfg, httpClient: &http.Client{Timeout: httpClientTimeout}}
+}
+
+func (d *WhatsAppDeliverer) Channel() DeliveryChannel { return ChannelWhatsApp }
+
+func (d *WhatsAppDeliverer) Deliver(ctx context.Context, user models.User, token models.Token) error {
+	if user.PhoneNumber == "" {
+		return fmt.Errorf("user %s has no phone number on file", user.ID)
+	}
+
+	if d.cfg.Provider != config.SMSProviderTwilio {
+		return fmt.Errorf("whatsapp delivery requires the twilio provider, got %q", d.cfg.Provider)
+	}
+
+	endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", d.cfg.Twilio.AccountSID)
+
+	form := url.Values{
+		"To":   {"whatsapp:" + user.PhoneNumber},
+		"From": {"whatsapp:" + d.cfg.Twilio.FromNumber},
+		"Body": {token.Value},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(d.cfg.Twilio.AccountSID, d.cfg.Twilio.AuthToken)
+
+	return doDeliveryRequest(d.httpClient, req, "twilio whatsapp")
+}
+
+// doDeliveryRequest executes req and turns a non-2xx response into an
+// error, so a provider-side rejection surfaces to Create instead of being
+// mistaken for a successful delivery.
+func doDeliveryRequest(client *http.Client, req *http.Request, provider string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s delivery request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s delivery request failed with status %d", provider, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// WebPushDeliverer sends the token as a Web Push notification to the
+// user's subscribed browser endpoints.
+type WebPushDeliverer struct {
+	cfg config.WebPush
+}
+
+// NewWebPushDeliverer creates a WebPushDeliverer.
+func NewWebPushDeliverer(cfg config.WebPush) *WebPushDeliverer {
+	return &WebPushDeliverer{cfg: cfg}
+}
+
+func (d *WebPushDeliverer) Channel() DeliveryChannel { return ChannelPush }
+
+// Deliver is not implemented yet: VAPID-signed, encrypted web push payloads
+// need a real subscription store (endpoint + keys per browser) that doesn't
+// exist in this package yet. It errors rather than silently reporting
+// success, so Create surfaces the failure instead of claiming a token was
+// delivered when it wasn't.
+func (d *WebPushDeliverer) Deliver(ctx context.Context, user models.User, token models.Token) error {
+	return fmt.Errorf("web push delivery is not implemented yet")
+}
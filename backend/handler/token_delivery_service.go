@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/teamhanko/hanko/backend/persistence/models"
+)
+
+// TokenDeliveryService picks the right TokenDeliverer for a token issuance
+// request and hands the token off to it.
+type TokenDeliveryService struct {
+	deliverers map[DeliveryChannel]TokenDeliverer
+}
+
+// NewTokenDeliveryService creates a TokenDeliveryService from the given set
+// of channel implementations.
+func NewTokenDeliveryService(deliverers ...TokenDeliverer) *TokenDeliveryService {
+	byChannel := make(map[DeliveryChannel]TokenDeliverer, len(deliverers))
+	for _, d := range deliverers {
+		byChannel[d.Channel()] = d
+	}
+	return &TokenDeliveryService{deliverers: byChannel}
+}
+
+// Deliver resolves the channel to use for this issuance - the explicit
+// request hint if given and supported, otherwise the user's preferred
+// channel, otherwise email - and sends the token over it.
+func (s *TokenDeliveryService) Deliver(ctx context.Context, user models.User, token models.Token, requestedChannel DeliveryChannel) (DeliveryChannel, error) {
+	channel := s.resolveChannel(user, requestedChannel)
+
+	deliverer, ok := s.deliverers[channel]
+	if !ok {
+		return channel, fmt.Errorf("no deliverer configured for channel %q", channel)
+	}
+
+	if err := deliverer.Deliver(ctx, user, token); err != nil {
+		return channel, err
+	}
+
+	return channel, nil
+}
+
+func (s *TokenDeliveryService) resolveChannel(user models.User, requestedChannel DeliveryChannel) DeliveryChannel {
+	if requestedChannel != "" {
+		if _, ok := s.deliverers[requestedChannel]; ok {
+			return requestedChannel
+		}
+	}
+
+	if user.PreferredChannel != "" {
+		if channel := DeliveryChannel(user.PreferredChannel); s.deliverers[channel] != nil {
+			return channel
+		}
+	}
+
+	return ChannelEmail
+}
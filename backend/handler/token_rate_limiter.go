@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/teamhanko/hanko/backend/config"
+)
+
+// TokenIssuanceRateLimiter throttles token issuance per (user, channel, ip)
+// bucket using a leaky bucket algorithm.
+type TokenIssuanceRateLimiter interface {
+	// Allow reports whether a new token may be issued for the given key.
+	Allow(key string) bool
+}
+
+// NewTokenIssuanceRateLimiter creates a TokenIssuanceRateLimiter from
+// config. Only the in-process limiter is implemented today: a Redis-backed
+// limiter needs a real client and a Lua script to keep the check atomic
+// across replicas, neither of which exist in this package yet. Rather than
+// hand back a limiter that silently allows everything, enabling
+// cfg.Redis.Enabled is a hard error until that's built.
+func NewTokenIssuanceRateLimiter(cfg config.RateLimiting) (TokenIssuanceRateLimiter, error) {
+	if cfg.Redis.Enabled {
+		return nil, fmt.Errorf("redis-backed token issuance rate limiting is not implemented yet; disable rate_limiting.redis.enabled")
+	}
+	return newInMemoryLeakyBucketLimiter(cfg), nil
+}
+
+// RateLimitKey builds the bucket key a rate limit check is performed
+// against.
+func RateLimitKey(userId string, channel DeliveryChannel, ip string) string {
+	return userId + "|" + string(channel) + "|" + ip
+}
+
+type leakyBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+type inMemoryLeakyBucketLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*leakyBucket
+	rate    float64 // tokens added per second
+	burst   float64
+}
+
+func newInMemoryLeakyBucketLimiter(cfg config.RateLimiting) *inMemoryLeakyBucketLimiter {
+	interval := cfg.IntervalSeconds
+	if interval <= 0 {
+		interval = 60
+	}
+	requests := cfg.Requests
+	if requests <= 0 {
+		requests = 1
+	}
+
+	return &inMemoryLeakyBucketLimiter{
+		buckets: make(map[string]*leakyBucket),
+		rate:    float64(requests) / float64(interval),
+		burst:   float64(requests),
+	}
+}
+
+func (l *inMemoryLeakyBucketLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &leakyBucket{tokens: l.burst, lastRefill: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.tokens = minFloat(l.burst, b.tokens+elapsed*l.rate)
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
@@ -12,28 +13,113 @@ import (
 	"github.com/teamhanko/hanko/backend/persistence"
 	"github.com/teamhanko/hanko/backend/persistence/models"
 	"github.com/teamhanko/hanko/backend/test"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
+// discardLogger is used by tests that don't care about emitted log
+// records, so they don't have to thread a *slog.Logger through every
+// NewTokenHandler call.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// defaultConfig is the zero-value config.Config shared by tests that call
+// setupConfig, which tweaks just the fields a given test cares about.
+var defaultConfig config.Config
+
+// sessionManager is a session.Manager test double that issues a
+// deterministic JWT and cookie without any real signing, so handler tests
+// don't need a configured session manager to exercise token exchange.
+type sessionManager struct{}
+
+func (sessionManager) GenerateJWT(userId uuid.UUID) (string, error) {
+	return "test-session-jwt-" + userId.String(), nil
+}
+
+func (sessionManager) GenerateCookie(token string) (*http.Cookie, error) {
+	return &http.Cookie{Name: "hanko", Value: token}, nil
+}
+
+func (sessionManager) Invalidate(token string) error {
+	return nil
+}
+
+// recordingHandler is a minimal slog.Handler that keeps every record it
+// sees, so tests can assert on the structured fields a handler emitted.
+type recordingHandler struct {
+	mu      *sync.Mutex
+	records *[]slog.Record
+	attrs   []slog.Attr
+}
+
+func newRecordingLogger() (*slog.Logger, *recordingHandler) {
+	h := &recordingHandler{mu: &sync.Mutex{}, records: &[]slog.Record{}}
+	return slog.New(h), h
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	r.AddAttrs(h.attrs...)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordingHandler{mu: h.mu, records: h.records, attrs: append(h.attrs, attrs...)}
+}
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+func (h *recordingHandler) Records() []slog.Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]slog.Record{}, *h.records...)
+}
+
+func attrValue(r slog.Record, key string) (string, bool) {
+	var value string
+	var found bool
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == key {
+			value = a.Value.String()
+			found = true
+			return false
+		}
+		return true
+	})
+	return value, found
+}
+
+// TestTokenSuite runs tokenSuite once per dialect returned by
+// test.TestDialects, each as its own named sub-test. In short mode that's
+// just the in-process SQLite dialect; otherwise it's whichever
+// TEST_DATABASE_* environment variables are set.
 func TestTokenSuite(t *testing.T) {
-	suite.Run(t, new(tokenSuite))
+	for _, dialect := range test.TestDialects(testing.Short()) {
+		dialect := dialect
+		t.Run(dialect, func(t *testing.T) {
+			suite.Run(t, &tokenSuite{dialect: dialect})
+		})
+	}
 }
 
 type tokenSuite struct {
 	suite.Suite
+	dialect string
 	storage persistence.Storage
 	db      *test.TestDB
 }
 
 func (s *tokenSuite) SetupSuite() {
-	if testing.Short() {
-		return
-	}
-	dialect := "postgres"
-	db, err := test.StartDB("token_test", dialect)
+	db, err := test.StartDB("token_test", s.dialect)
 	s.NoError(err)
 	storage, err := persistence.New(config.Database{
 		Url: db.DatabaseUrl,
@@ -45,17 +131,13 @@ func (s *tokenSuite) SetupSuite() {
 }
 
 func (s *tokenSuite) SetupTest() {
-	if s.db != nil {
-		err := s.storage.MigrateUp()
-		s.NoError(err)
-	}
+	err := s.storage.MigrateUp()
+	s.NoError(err)
 }
 
 func (s *tokenSuite) TearDownTest() {
-	if s.db != nil {
-		err := s.storage.MigrateDown(-1)
-		s.NoError(err)
-	}
+	err := s.storage.MigrateDown(-1)
+	s.NoError(err)
 }
 
 func (s *tokenSuite) TearDownSuite() {
@@ -65,10 +147,6 @@ func (s *tokenSuite) TearDownSuite() {
 }
 
 func (s *tokenSuite) TestToken_Validate() {
-	if testing.Short() {
-		s.T().Skip("skipping test in short mode.")
-	}
-
 	err := test.LoadFixtures(s.db.DbCon, s.db.Dialect, "../test/fixtures/token")
 
 	e := echo.New()
@@ -93,11 +171,12 @@ func (s *tokenSuite) TestToken_Validate() {
 
 	cfg := s.setupConfig()
 	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
-	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
 	if s.NoError(handler.Validate(c)) {
 		t, err := s.storage.GetTokenPersister().GetByValue(token.Value)
 		s.NoError(err)
-		s.Nil(t)
+		s.NotNil(t)
+		s.True(t.IsUsed())
 
 		setCookie := rec.Header().Get("Set-Cookie")
 		s.True(strings.HasPrefix(setCookie, "hanko"))
@@ -111,10 +190,6 @@ func (s *tokenSuite) TestToken_Validate() {
 }
 
 func (s *tokenSuite) TestToken_Validate_ExpiredToken() {
-	if testing.Short() {
-		s.T().Skip("skipping test in short mode.")
-	}
-
 	err := test.LoadFixtures(s.db.DbCon, s.db.Dialect, "../test/fixtures/token")
 
 	e := echo.New()
@@ -132,7 +207,7 @@ func (s *tokenSuite) TestToken_Validate_ExpiredToken() {
 
 	cfg := s.setupConfig()
 	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
-	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
 	err = handler.Validate(c)
 	if s.Error(err) {
 		herr, ok := err.(*dto.HTTPError)
@@ -147,10 +222,6 @@ func (s *tokenSuite) TestToken_Validate_ExpiredToken() {
 }
 
 func (s *tokenSuite) TestToken_Validate_MissingTokenFromRequest() {
-	if testing.Short() {
-		s.T().Skip("skipping test in short mode.")
-	}
-
 	e := echo.New()
 	e.Validator = dto.NewCustomValidator()
 
@@ -161,7 +232,7 @@ func (s *tokenSuite) TestToken_Validate_MissingTokenFromRequest() {
 
 	cfg := s.setupConfig()
 	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
-	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
 	err := handler.Validate(c)
 	if s.Error(err) {
 		herr, ok := err.(*dto.HTTPError)
@@ -176,10 +247,6 @@ func (s *tokenSuite) TestToken_Validate_MissingTokenFromRequest() {
 }
 
 func (s *tokenSuite) TestToken_Validate_InvalidJson() {
-	if testing.Short() {
-		s.T().Skip("skipping test in short mode.")
-	}
-
 	e := echo.New()
 	e.Validator = dto.NewCustomValidator()
 	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader([]byte("invalid")))
@@ -189,7 +256,7 @@ func (s *tokenSuite) TestToken_Validate_InvalidJson() {
 
 	cfg := s.setupConfig()
 	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
-	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
 	err := handler.Validate(c)
 	if s.Error(err) {
 		herr, ok := err.(*dto.HTTPError)
@@ -203,10 +270,6 @@ func (s *tokenSuite) TestToken_Validate_InvalidJson() {
 }
 
 func (s *tokenSuite) TestToken_Validate_TokenNotFound() {
-	if testing.Short() {
-		s.T().Skip("skipping test in short mode.")
-	}
-
 	e := echo.New()
 	e.Validator = dto.NewCustomValidator()
 
@@ -225,7 +288,7 @@ func (s *tokenSuite) TestToken_Validate_TokenNotFound() {
 
 	cfg := s.setupConfig()
 	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
-	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
 	err = handler.Validate(c)
 	if s.Error(err) {
 		herr, ok := err.(*dto.HTTPError)
@@ -239,9 +302,593 @@ func (s *tokenSuite) TestToken_Validate_TokenNotFound() {
 	}
 }
 
+func (s *tokenSuite) TestToken_Validate_PKCE_Success() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := codeChallengeS256(verifier)
+	method := codeChallengeMethodS256
+	token.CodeChallenge = &challenge
+	token.CodeChallengeMethod = &method
+	s.NoError(s.storage.GetTokenPersister().Create(*token))
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenValidationBody{Value: token.Value, CodeVerifier: verifier}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Validate(c)) {
+		s.Equal(http.StatusOK, rec.Code)
+
+		t, err := s.storage.GetTokenPersister().GetByValue(token.Value)
+		s.NoError(err)
+		s.True(t.IsUsed())
+	}
+}
+
+func (s *tokenSuite) TestToken_Validate_PKCE_MissingVerifier() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+
+	challenge := codeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	method := codeChallengeMethodS256
+	token.CodeChallenge = &challenge
+	token.CodeChallengeMethod = &method
+	s.NoError(s.storage.GetTokenPersister().Create(*token))
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenValidationBody{Value: token.Value}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	err = handler.Validate(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusBadRequest, herr.Code)
+		s.Equal("invalid_grant", herr.Message)
+
+		t, gerr := s.storage.GetTokenPersister().GetByValue(token.Value)
+		s.NoError(gerr)
+		s.False(t.IsUsed())
+
+		logs, lerr := s.storage.GetAuditLogPersister().List(0, 0, nil, nil, []string{"token_pkce_missing"}, uId.String(), "", "", "")
+		s.NoError(lerr)
+		s.Len(logs, 1)
+	}
+}
+
+func (s *tokenSuite) TestToken_Validate_PKCE_WrongVerifier() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+
+	challenge := codeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	method := codeChallengeMethodS256
+	token.CodeChallenge = &challenge
+	token.CodeChallengeMethod = &method
+	s.NoError(s.storage.GetTokenPersister().Create(*token))
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenValidationBody{Value: token.Value, CodeVerifier: "wrong-verifier-wrong-verifier-wrong-verifier"}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	err = handler.Validate(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusBadRequest, herr.Code)
+		s.Equal("invalid_grant", herr.Message)
+
+		t, gerr := s.storage.GetTokenPersister().GetByValue(token.Value)
+		s.NoError(gerr)
+		s.False(t.IsUsed())
+
+		logs, lerr := s.storage.GetAuditLogPersister().List(0, 0, nil, nil, []string{"token_pkce_mismatch"}, uId.String(), "", "", "")
+		s.NoError(lerr)
+		s.Len(logs, 1)
+	}
+}
+
+func (s *tokenSuite) TestToken_Create_PKCE_MalformedChallenge() {
+	user := models.User{ID: uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5"), Email: "user@example.com"}
+	s.NoError(s.storage.GetUserPersister().Create(user))
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenCreationBody{UserId: user.ID.String(), CodeChallenge: "some-challenge", CodeChallengeMethod: "plain"}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	err = handler.Create(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusBadRequest, herr.Code)
+	}
+}
+
+func (s *tokenSuite) TestToken_Create_RequirePKCE() {
+	user := models.User{ID: uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5"), Email: "user@example.com"}
+	s.NoError(s.storage.GetUserPersister().Create(user))
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenCreationBody{UserId: user.ID.String()}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	cfg.Token.RequirePKCE = true
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	err = handler.Create(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusBadRequest, herr.Code)
+	}
+}
+
+func (s *tokenSuite) TestToken_Revoke_Success() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+	err = s.storage.GetTokenPersister().Create(*token)
+	s.NoError(err)
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenRevocationBody{Token: token.Value}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token/revoke", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Revoke(c)) {
+		s.Equal(http.StatusOK, rec.Code)
+
+		logs, lerr := s.storage.GetAuditLogPersister().List(0, 0, nil, nil, []string{"token_revoked"}, "b5dd5267-b462-48be-b70d-bcd6f1bbe7a5", "", "", "")
+		s.NoError(lerr)
+		s.Len(logs, 1)
+
+		// a subsequent exchange of the revoked token must fail
+		validateBody := TokenValidationBody{Value: token.Value}
+		validateBodyJson, verr := json.Marshal(validateBody)
+		s.NoError(verr)
+		validateReq := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(validateBodyJson))
+		validateReq.Header.Set("Content-Type", "application/json")
+		validateRec := httptest.NewRecorder()
+		validateC := e.NewContext(validateReq, validateRec)
+
+		verr = handler.Validate(validateC)
+		if s.Error(verr) {
+			herr, ok := verr.(*dto.HTTPError)
+			s.True(ok)
+			s.Equal(http.StatusNotFound, herr.Code)
+		}
+	}
+}
+
+func (s *tokenSuite) TestToken_Revoke_UnknownToken() {
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenRevocationBody{Token: "does-not-exist"}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token/revoke", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Revoke(c)) {
+		s.Equal(http.StatusOK, rec.Code)
+	}
+}
+
+func (s *tokenSuite) TestToken_Revoke_MalformedJson() {
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	req := httptest.NewRequest(http.MethodPost, "/token/revoke", bytes.NewReader([]byte("invalid")))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	err := handler.Revoke(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusBadRequest, herr.Code)
+	}
+}
+
+func (s *tokenSuite) TestToken_Introspect_Active() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+	err = s.storage.GetTokenPersister().Create(*token)
+	s.NoError(err)
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenIntrospectionBody{Token: token.Value}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token/introspect", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Introspect(c)) {
+		var resp TokenIntrospectionResponse
+		s.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+		s.True(resp.Active)
+		s.Equal(uId.String(), resp.Sub)
+	}
+}
+
+func (s *tokenSuite) TestToken_Introspect_ExpiredToken() {
+	err := test.LoadFixtures(s.db.DbCon, s.db.Dialect, "../test/fixtures/token")
+	s.NoError(err)
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenIntrospectionBody{Token: "Trkauhl3q7XVxw5JcDH80lTe1KxzydIw0OcizH7umWk="}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token/introspect", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Introspect(c)) {
+		var resp TokenIntrospectionResponse
+		s.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+		s.False(resp.Active)
+	}
+}
+
+func (s *tokenSuite) TestToken_Introspect_UsedToken() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+	s.NoError(s.storage.GetTokenPersister().Create(*token))
+
+	_, won, err := s.storage.GetTokenPersister().MarkUsed(token.Value, "some-jwt")
+	s.NoError(err)
+	s.True(won)
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenIntrospectionBody{Token: token.Value}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token/introspect", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Introspect(c)) {
+		var resp TokenIntrospectionResponse
+		s.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+		s.False(resp.Active)
+	}
+}
+
+func (s *tokenSuite) TestToken_Introspect_UnknownToken() {
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenIntrospectionBody{Token: "does-not-exist"}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token/introspect", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	if s.NoError(handler.Introspect(c)) {
+		var resp TokenIntrospectionResponse
+		s.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+		s.False(resp.Active)
+	}
+}
+
+func (s *tokenSuite) TestToken_Introspect_MalformedJson() {
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	req := httptest.NewRequest(http.MethodPost, "/token/introspect", bytes.NewReader([]byte("invalid")))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+	err := handler.Introspect(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusBadRequest, herr.Code)
+	}
+}
+
+func (s *tokenSuite) TestToken_Validate_ConcurrentReplay() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+	err = s.storage.GetTokenPersister().Create(*token)
+	s.NoError(err)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, nil)
+
+	const parallelism = 10
+	var wg sync.WaitGroup
+	var succeeded, conflicted int32
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			e := echo.New()
+			e.Validator = dto.NewCustomValidator()
+
+			body := TokenValidationBody{Value: token.Value}
+			bodyJson, merr := json.Marshal(body)
+			s.NoError(merr)
+
+			req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			verr := handler.Validate(c)
+			if verr == nil {
+				atomic.AddInt32(&succeeded, 1)
+				return
+			}
+
+			herr, ok := verr.(*dto.HTTPError)
+			if ok && herr.Code == http.StatusConflict {
+				atomic.AddInt32(&conflicted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.EqualValues(1, succeeded)
+	s.EqualValues(parallelism-1, conflicted)
+
+	logs, lerr := s.storage.GetAuditLogPersister().List(0, 0, nil, nil, []string{"token_reuse_detected"}, "b5dd5267-b462-48be-b70d-bcd6f1bbe7a5", "", "", "")
+	s.NoError(lerr)
+	s.Len(logs, int(parallelism-1))
+}
+
+func (s *tokenSuite) TestToken_Validate_EmitsStructuredLogs() {
+	uId := uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5")
+	token, err := models.NewToken(uId)
+	s.NoError(err)
+	err = s.storage.GetTokenPersister().Create(*token)
+	s.NoError(err)
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenValidationBody{Value: token.Value}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Response().Header().Set(echo.HeaderXRequestID, "req-123")
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	logger, recorder := newRecordingLogger()
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, logger, nil, nil)
+	if s.NoError(handler.Validate(c)) {
+		records := recorder.Records()
+		s.Len(records, 1)
+
+		requestId, ok := attrValue(records[0], "request_id")
+		s.True(ok)
+		s.Equal("req-123", requestId)
+
+		remoteIp, ok := attrValue(records[0], "remote_ip")
+		s.True(ok)
+		s.Equal("203.0.113.7", remoteIp)
+
+		userId, ok := attrValue(records[0], "user_id")
+		s.True(ok)
+		s.Equal(uId.String(), userId)
+
+		tokenId, ok := attrValue(records[0], "token_id")
+		s.True(ok)
+		s.NotEqual(token.Value, tokenId)
+		s.Equal(hashTokenValue(token.Value), tokenId)
+	}
+}
+
+// fakeDeliverer is a TokenDeliverer test double that records the tokens it
+// was asked to deliver instead of reaching out to a real provider.
+type fakeDeliverer struct {
+	channel   DeliveryChannel
+	delivered []models.Token
+	err       error
+}
+
+func (d *fakeDeliverer) Channel() DeliveryChannel { return d.channel }
+
+func (d *fakeDeliverer) Deliver(_ context.Context, _ models.User, token models.Token) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.delivered = append(d.delivered, token)
+	return nil
+}
+
+// alwaysDenyRateLimiter is a TokenIssuanceRateLimiter test double that
+// rejects every request.
+type alwaysDenyRateLimiter struct{}
+
+func (alwaysDenyRateLimiter) Allow(string) bool { return false }
+
+func (s *tokenSuite) TestToken_Create_UsesRequestedChannel() {
+	user := models.User{ID: uuid.FromStringOrNil("b5dd5267-b462-48be-b70d-bcd6f1bbe7a5"), Email: "user@example.com", PhoneNumber: "+10000000000"}
+	err := s.storage.GetUserPersister().Create(user)
+	s.NoError(err)
+
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenCreationBody{UserId: user.ID.String(), Channel: "sms"}
+	bodyJson, merr := json.Marshal(body)
+	s.NoError(merr)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	emailDeliverer := &fakeDeliverer{channel: ChannelEmail}
+	smsDeliverer := &fakeDeliverer{channel: ChannelSMS}
+	deliveryService := NewTokenDeliveryService(emailDeliverer, smsDeliverer)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, deliveryService, nil)
+
+	err = handler.Create(c)
+	if s.NoError(err) {
+		s.Equal(http.StatusCreated, rec.Code)
+		s.Len(smsDeliverer.delivered, 1)
+		s.Empty(emailDeliverer.delivered)
+	}
+}
+
+func (s *tokenSuite) TestToken_Create_RateLimited() {
+	e := echo.New()
+	e.Validator = dto.NewCustomValidator()
+
+	body := TokenCreationBody{UserId: "b5dd5267-b462-48be-b70d-bcd6f1bbe7a5", Channel: "email"}
+	bodyJson, err := json.Marshal(body)
+	s.NoError(err)
+
+	req := httptest.NewRequest(http.MethodPost, "/token", bytes.NewReader(bodyJson))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := s.setupConfig()
+	auditLogger := auditlog.NewLogger(s.storage, cfg.AuditLog)
+	handler := NewTokenHandler(cfg, s.storage, sessionManager{}, auditLogger, discardLogger, nil, alwaysDenyRateLimiter{})
+
+	err = handler.Create(c)
+	if s.Error(err) {
+		herr, ok := err.(*dto.HTTPError)
+		s.True(ok)
+		s.Equal(http.StatusTooManyRequests, herr.Code)
+
+		logs, lerr := s.storage.GetAuditLogPersister().List(0, 0, nil, nil, []string{"token_issuance_rate_limited"}, "b5dd5267-b462-48be-b70d-bcd6f1bbe7a5", "", "", "")
+		s.NoError(lerr)
+		s.Len(logs, 1)
+	}
+}
+
 func (s *tokenSuite) setupConfig() *config.Config {
-	cfg := &defaultConfig
+	cfg := defaultConfig
 	cfg.Session.EnableAuthTokenHeader = true
 	cfg.AuditLog.Storage.Enabled = true
-	return cfg
+	return &cfg
 }
\ No newline at end of file
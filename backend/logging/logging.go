@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/teamhanko/hanko/backend/config"
+)
+
+// NewLogger builds the root slog.Logger for the application from the
+// Logging configuration. Handlers derive their request-scoped child
+// loggers from this one via slog.Logger.With.
+func NewLogger(cfg config.Logging) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		AddSource: cfg.AddSource,
+		Level:     parseLevel(cfg.Level),
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, "json") {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
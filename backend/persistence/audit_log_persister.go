@@ -0,0 +1,64 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/teamhanko/hanko/backend/persistence/models"
+)
+
+// AuditLogPersister provides persistence operations for audit log entries.
+type AuditLogPersister interface {
+	Create(entry models.AuditLog) error
+	List(page, perPage int, startTime, endTime *time.Time, types []string, userId, email, ip, country string) ([]models.AuditLog, error)
+}
+
+type auditLogPersister struct {
+	db *pop.Connection
+}
+
+// NewAuditLogPersister creates an AuditLogPersister backed by the given
+// connection.
+func NewAuditLogPersister(db *pop.Connection) AuditLogPersister {
+	return &auditLogPersister{db: db}
+}
+
+func (p *auditLogPersister) Create(entry models.AuditLog) error {
+	return p.db.Create(&entry)
+}
+
+func (p *auditLogPersister) List(page, perPage int, startTime, endTime *time.Time, types []string, userId, email, ip, country string) ([]models.AuditLog, error) {
+	entries := []models.AuditLog{}
+	query := p.db.Q()
+
+	if len(types) > 0 {
+		query = query.Where("type in (?)", types)
+	}
+
+	if userId != "" {
+		query = query.Where("user_id = ?", userId)
+	}
+
+	if ip != "" {
+		query = query.Where("actor_ip = ?", ip)
+	}
+
+	if startTime != nil {
+		query = query.Where("created_at >= ?", *startTime)
+	}
+
+	if endTime != nil {
+		query = query.Where("created_at <= ?", *endTime)
+	}
+
+	if page > 0 && perPage > 0 {
+		query = query.Paginate(page, perPage)
+	}
+
+	err := query.All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
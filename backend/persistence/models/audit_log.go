@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// AuditLog represents a single recorded security relevant event.
+type AuditLog struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Type      string    `json:"type" db:"type"`
+	UserId    string    `json:"user_id" db:"user_id"`
+	ActorIp   string    `json:"actor_ip" db:"actor_ip"`
+	Error     string    `json:"error" db:"error"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewAuditLog creates a new AuditLog entry ready for persistence.
+func NewAuditLog(logType string, userId string, actorIp string, errorMessage string) (*AuditLog, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditLog{
+		ID:        id,
+		Type:      logType,
+		UserId:    userId,
+		ActorIp:   actorIp,
+		Error:     errorMessage,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
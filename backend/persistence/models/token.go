@@ -0,0 +1,86 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// TokenLifespan is the duration a one-time token remains valid for exchange
+// after it has been issued.
+const TokenLifespan = 5 * time.Minute
+
+// Token represents a one-time token that can be exchanged for a session,
+// e.g. after a successful third party sign-in callback.
+//
+// UsedAt and Generation together guard against replay: an exchange only
+// succeeds if it is the row's first update (UsedAt is still nil), so a
+// concurrent double-submit can only ever win the race once. Generation is
+// bumped on every successful exchange, which gives callers a stable value
+// to compare against when they need to tell which session an exchange
+// produced, without relying on timestamp ordering.
+type Token struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserId     uuid.UUID  `json:"user_id" db:"user_id"`
+	Value      string     `json:"value" db:"value"`
+	Generation int        `json:"generation" db:"generation"`
+	UsedAt     *time.Time `json:"used_at" db:"used_at"`
+	// IssuedSessionJWT is the session token produced by the exchange that
+	// won the claim. It is kept around so a detected replay can invalidate
+	// that session, not just reject the second exchange.
+	IssuedSessionJWT string `json:"-" db:"issued_session_jwt"`
+	// CodeChallenge and CodeChallengeMethod bind the token to a PKCE
+	// (RFC 7636) code_verifier the caller must present on exchange. Both
+	// are nullable: binding is opt-in per token, so tokens issued without
+	// a challenge keep validating exactly as before.
+	CodeChallenge       *string   `json:"-" db:"code_challenge"`
+	CodeChallengeMethod *string   `json:"-" db:"code_challenge_method"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+	ExpiresAt           time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// NewToken creates a new one-time token for the given user with a
+// cryptographically random value and the default token lifespan.
+func NewToken(userId uuid.UUID) (*Token, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := generateRandomValue()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	return &Token{
+		ID:        id,
+		UserId:    userId,
+		Value:     value,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(TokenLifespan),
+	}, nil
+}
+
+// IsExpired reports whether the token is no longer valid for exchange.
+func (t *Token) IsExpired() bool {
+	return time.Now().UTC().After(t.ExpiresAt)
+}
+
+// IsUsed reports whether the token has already been exchanged once.
+func (t *Token) IsUsed() bool {
+	return t.UsedAt != nil
+}
+
+func generateRandomValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
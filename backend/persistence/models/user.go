@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// User represents a registered account.
+type User struct {
+	ID uuid.UUID `json:"id" db:"id"`
+
+	Email       string `json:"email" db:"email"`
+	PhoneNumber string `json:"phone_number" db:"phone_number"`
+
+	// PreferredChannel is the delivery channel token issuance falls back
+	// to when a request doesn't specify one explicitly.
+	PreferredChannel string `json:"preferred_channel" db:"preferred_channel"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
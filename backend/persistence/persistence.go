@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"path/filepath"
+	"runtime"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/teamhanko/hanko/backend/config"
+)
+
+// Storage gives handlers access to all persisters backed by a single
+// database connection.
+type Storage interface {
+	GetTokenPersister() TokenPersister
+	GetAuditLogPersister() AuditLogPersister
+	GetUserPersister() UserPersister
+	MigrateUp() error
+	MigrateDown(step int) error
+}
+
+type storage struct {
+	db *pop.Connection
+}
+
+// New creates a Storage backed by a database connection opened from the
+// given configuration.
+func New(cfg config.Database) (Storage, error) {
+	db, err := pop.NewConnection(&pop.ConnectionDetails{
+		URL: cfg.Url,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+
+	return &storage{db: db}, nil
+}
+
+func (s *storage) GetTokenPersister() TokenPersister {
+	return NewTokenPersister(s.db)
+}
+
+func (s *storage) GetAuditLogPersister() AuditLogPersister {
+	return NewAuditLogPersister(s.db)
+}
+
+func (s *storage) GetUserPersister() UserPersister {
+	return NewUserPersister(s.db)
+}
+
+// migrationPath is the absolute path to the fizz files that ship with the
+// persistence layer. It's resolved from this file's own location, rather
+// than a path relative to the working directory, because callers (e.g.
+// handler package tests) run with a working directory that isn't this
+// package's.
+var migrationPath = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "migration")
+}()
+
+func (s *storage) MigrateUp() error {
+	migrator, err := pop.NewFileMigrator(migrationPath, s.db)
+	if err != nil {
+		return err
+	}
+	return migrator.Up()
+}
+
+func (s *storage) MigrateDown(step int) error {
+	migrator, err := pop.NewFileMigrator(migrationPath, s.db)
+	if err != nil {
+		return err
+	}
+	return migrator.Down(step)
+}
@@ -0,0 +1,128 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/teamhanko/hanko/backend/persistence/models"
+)
+
+// TokenPersister provides persistence operations for one-time tokens.
+type TokenPersister interface {
+	Create(token models.Token) error
+	GetByValue(value string) (*models.Token, error)
+	Delete(token models.Token) error
+	// MarkUsed atomically claims the token identified by value, i.e. it
+	// only flips UsedAt/Generation/IssuedSessionJWT on the row's first
+	// claim. won reports whether this call was the one that claimed it;
+	// token is the row's state after the attempt (nil if no token has that
+	// value at all).
+	MarkUsed(value string, issuedSessionJWT string) (token *models.Token, won bool, err error)
+}
+
+type tokenPersister struct {
+	db *pop.Connection
+}
+
+// NewTokenPersister creates a TokenPersister backed by the given connection.
+func NewTokenPersister(db *pop.Connection) TokenPersister {
+	return &tokenPersister{db: db}
+}
+
+func (p *tokenPersister) Create(token models.Token) error {
+	return p.db.Create(&token)
+}
+
+func (p *tokenPersister) GetByValue(value string) (*models.Token, error) {
+	token := models.Token{}
+	err := p.db.Where("value = ?", value).First(&token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (p *tokenPersister) Delete(token models.Token) error {
+	return p.db.Destroy(&token)
+}
+
+// MarkUsed performs the claim as a single `UPDATE ... WHERE used_at IS NULL`
+// statement (with `RETURNING *` on dialects that support it) so that of any
+// number of concurrent callers racing on the same token value, exactly one
+// observes won == true. It runs outside of an explicit transaction: the
+// atomicity comes from the single conditional UPDATE, not from locking.
+//
+// MySQL has no `RETURNING` clause, so it claims the row via an explicit
+// transaction instead (see markUsedMySQL): the UPDATE's affected-row count
+// stands in for won, and a SELECT inside the same transaction reads the
+// row back.
+func (p *tokenPersister) MarkUsed(value string, issuedSessionJWT string) (*models.Token, bool, error) {
+	now := time.Now().UTC()
+
+	if p.db.Dialect.Name() == "mysql" {
+		return p.markUsedMySQL(value, issuedSessionJWT, now)
+	}
+
+	token := models.Token{}
+
+	query := p.db.RawQuery(
+		`UPDATE tokens SET used_at = ?, generation = generation + 1, issued_session_jwt = ?, updated_at = ? WHERE value = ? AND used_at IS NULL RETURNING *`,
+		now, issuedSessionJWT, now, value,
+	)
+
+	err := query.First(&token)
+	if err == nil {
+		return &token, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	// Either the token doesn't exist, or it does but was already used by a
+	// concurrent caller. Distinguish the two so Validate can tell reuse
+	// apart from a plain not-found.
+	existing, getErr := p.GetByValue(value)
+	if getErr != nil {
+		return nil, false, getErr
+	}
+
+	return existing, false, nil
+}
+
+func (p *tokenPersister) markUsedMySQL(value string, issuedSessionJWT string, now time.Time) (*models.Token, bool, error) {
+	var won bool
+	token := models.Token{}
+	found := true
+
+	err := p.db.Transaction(func(tx *pop.Connection) error {
+		count, err := tx.RawQuery(
+			`UPDATE tokens SET used_at = ?, generation = generation + 1, issued_session_jwt = ?, updated_at = ? WHERE value = ? AND used_at IS NULL`,
+			now, issuedSessionJWT, now, value,
+		).ExecWithCount()
+		if err != nil {
+			return err
+		}
+		won = count > 0
+
+		err = tx.Where("value = ?", value).First(&token)
+		if errors.Is(err, sql.ErrNoRows) {
+			found = false
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, nil
+	}
+
+	return &token, won, nil
+}
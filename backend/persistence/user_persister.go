@@ -0,0 +1,42 @@
+package persistence
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/hanko/backend/persistence/models"
+)
+
+// UserPersister provides persistence operations for users.
+type UserPersister interface {
+	Create(user models.User) error
+	Get(id uuid.UUID) (*models.User, error)
+}
+
+type userPersister struct {
+	db *pop.Connection
+}
+
+// NewUserPersister creates a UserPersister backed by the given connection.
+func NewUserPersister(db *pop.Connection) UserPersister {
+	return &userPersister{db: db}
+}
+
+func (p *userPersister) Create(user models.User) error {
+	return p.db.Create(&user)
+}
+
+func (p *userPersister) Get(id uuid.UUID) (*models.User, error) {
+	user := models.User{}
+	err := p.db.Find(&user, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/hanko/backend/handler"
+)
+
+// RegisterTokenRoutes wires TokenHandler's endpoints onto the given echo
+// group: issuing, exchanging, revoking, and introspecting one-time tokens.
+func RegisterTokenRoutes(group *echo.Group, tokenHandler *handler.TokenHandler) {
+	group.POST("/token", tokenHandler.Create)
+	group.POST("/token/validate", tokenHandler.Validate)
+	group.POST("/token/revoke", tokenHandler.Revoke)
+	group.POST("/token/introspect", tokenHandler.Introspect)
+}
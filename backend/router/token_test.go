@@ -0,0 +1,27 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterTokenRoutes(t *testing.T) {
+	e := echo.New()
+	RegisterTokenRoutes(e.Group(""), nil)
+
+	routes := map[string]bool{}
+	for _, r := range e.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+
+	for _, want := range []string{
+		"POST /token",
+		"POST /token/validate",
+		"POST /token/revoke",
+		"POST /token/introspect",
+	} {
+		assert.True(t, routes[want], "expected route %q to be registered", want)
+	}
+}
@@ -0,0 +1,15 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+// Manager issues and invalidates session JWTs and the cookies that carry
+// them.
+type Manager interface {
+	GenerateJWT(userId uuid.UUID) (string, error)
+	GenerateCookie(token string) (*http.Cookie, error)
+	Invalidate(token string) error
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gobuffalo/pop/v6"
+)
+
+// TestDB wraps a disposable database used by a single test suite run.
+type TestDB struct {
+	DatabaseUrl string
+	Dialect     string
+	DbCon       *pop.Connection
+	sqliteFile  string
+}
+
+// StartDB provisions (or connects to) a throwaway database for the given
+// suite name and dialect. For DialectSQLite, that's a temp file on disk
+// rather than a server dialed over DatabaseUrl.
+func StartDB(suiteName string, dialect string) (*TestDB, error) {
+	if dialect == DialectSQLite {
+		return startSQLiteDB(suiteName)
+	}
+
+	url := databaseUrl(dialect)
+
+	db, err := pop.NewConnection(&pop.ConnectionDetails{
+		Dialect: dialect,
+		URL:     url,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+
+	return &TestDB{DatabaseUrl: url, Dialect: dialect, DbCon: db}, nil
+}
+
+func startSQLiteDB(suiteName string) (*TestDB, error) {
+	file, err := os.CreateTemp("", fmt.Sprintf("%s-*.sqlite3", suiteName))
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	url := fmt.Sprintf("sqlite3://%s", file.Name())
+
+	db, err := pop.NewConnection(&pop.ConnectionDetails{
+		Dialect: DialectSQLite,
+		URL:     url,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+
+	return &TestDB{DatabaseUrl: url, Dialect: DialectSQLite, DbCon: db, sqliteFile: file.Name()}, nil
+}
+
+// PurgeDB tears down everything created for the given TestDB.
+func PurgeDB(db *TestDB) error {
+	if db == nil || db.DbCon == nil {
+		return nil
+	}
+
+	if err := db.DbCon.Close(); err != nil {
+		return err
+	}
+
+	if db.sqliteFile != "" {
+		return os.Remove(db.sqliteFile)
+	}
+
+	return nil
+}
+
+// LoadFixtures loads the yaml fixtures found under path into the database
+// identified by dialect.
+func LoadFixtures(db *pop.Connection, dialect string, path string) error {
+	return loadFixtures(db, dialect, path)
+}
@@ -0,0 +1,33 @@
+package test
+
+import "os"
+
+// TestDialects returns the dialects a suite should run its sub-tests
+// against. In short mode it returns just the in-process SQLite dialect, so
+// `go test -short` exercises the full validation logic without a database
+// server. Outside short mode it returns one entry per
+// TEST_DATABASE_<DIALECT> environment variable that's set, mirroring how
+// contributors opt individual dialects into CI.
+func TestDialects(short bool) []string {
+	if short {
+		return []string{DialectSQLite}
+	}
+
+	var dialects []string
+	if os.Getenv("TEST_DATABASE_POSTGRESQL") != "" {
+		dialects = append(dialects, "postgres")
+	}
+	if os.Getenv("TEST_DATABASE_MYSQL") != "" {
+		dialects = append(dialects, "mysql")
+	}
+	if os.Getenv("TEST_DATABASE_COCKROACHDB") != "" {
+		dialects = append(dialects, "cockroach")
+	}
+
+	return dialects
+}
+
+// DialectSQLite is the in-process dialect used for `-short` test runs. It
+// needs no TEST_DATABASE_* environment variable: StartDB opens an
+// throwaway on-disk file instead of dialing a server.
+const DialectSQLite = "sqlite3"
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/go-testfixtures/testfixtures/v3"
+)
+
+func databaseUrl(dialect string) string {
+	switch dialect {
+	case "mysql":
+		return os.Getenv("TEST_DATABASE_MYSQL")
+	case "cockroach":
+		return os.Getenv("TEST_DATABASE_COCKROACHDB")
+	default:
+		return os.Getenv("TEST_DATABASE_POSTGRESQL")
+	}
+}
+
+// sqlDriverName maps a pop dialect name to the database/sql driver name pop
+// itself uses to open connections for that dialect (see pop's DefaultDriver
+// on each dialect type), so loadFixtures can open its own *sql.DB against
+// the same database. pop.Connection.Store's concrete type is unexported,
+// so there's no way to recover a *sql.DB from an existing *pop.Connection.
+func sqlDriverName(dialect string) string {
+	switch dialect {
+	case "postgres", "cockroach":
+		return "pgx"
+	case "mysql":
+		return "mysql"
+	default:
+		return DialectSQLite
+	}
+}
+
+func loadFixtures(conn *pop.Connection, dialect string, path string) error {
+	sqlDB, err := sql.Open(sqlDriverName(dialect), conn.URL())
+	if err != nil {
+		return fmt.Errorf("opening fixture database connection: %w", err)
+	}
+	defer sqlDB.Close()
+
+	fixtures, err := testfixtures.New(
+		testfixtures.Database(sqlDB),
+		testfixtures.Dialect(dialect),
+		testfixtures.Directory(path),
+	)
+	if err != nil {
+		return err
+	}
+
+	return fixtures.Load()
+}
@@ -0,0 +1,8 @@
+package test
+
+// Registering the sqlite3 driver pulls in cgo. TestDialects unconditionally
+// selects it for -short runs, so it has to be a real dependency of the test
+// binary rather than opt-in behind a build tag — otherwise `go test -short`
+// fails at SetupSuite with "sql: unknown driver \"sqlite3\"" instead of
+// actually running anything.
+import _ "github.com/mattn/go-sqlite3"